@@ -0,0 +1,118 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package handler
+
+import (
+	. "common"
+	"fmt"
+	"github.com/hyperledger/burrow/acm"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/evm"
+	"github.com/hyperledger/burrow/permission"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+)
+
+// RegisterABI stores a compact JSON ABI for a contract address in the
+// on-chain ABI registry, so that SawtoothEventFireable.Log can decode that
+// contract's future events without subscribers needing their own copy of
+// the ABI. Only the contract itself or a Root-permissioned account may
+// register an entry, the same bar SetPermissions/ImportTxn use, so that an
+// unrelated account can't overwrite another contract's registered ABI.
+func RegisterABI(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppState) HandlerResult {
+	txn := wrapper.GetRegisterAbi()
+
+	senderAddr := crypto.AddressFromWord256(sender.ToWord256())
+	senderAcct, err := sapps.GetAccount(senderAddr)
+	if senderAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account must already exist to register an ABI: %v", sender,
+			)},
+		}
+	}
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
+	if txn.GetNonce() != senderConcrete.Sequence {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Nonces do not match: Transaction (%v), State (%v)",
+				txn.GetNonce(), senderConcrete.Sequence,
+			)},
+		}
+	}
+
+	contract, err := NewEvmAddrFromBytes(txn.GetAddress())
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Failed to construct contract address for ABI registration: %v", txn.GetAddress(),
+			)},
+		}
+	}
+
+	contractAddr := crypto.AddressFromWord256(contract.ToWord256())
+	if contractAddr != senderAddr && !evm.HasPermission(sapps, senderAddr, permission.Root) {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender does not have permission to register an ABI for %v: %v", contract, sender,
+			)},
+		}
+	}
+
+	abiAddr, err := NewAbiRegistryAddr(contract.Bytes())
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Failed to construct ABI registry address: %v", err,
+			)},
+		}
+	}
+
+	senderConcrete.Sequence += 1
+	if err := sapps.UpdateAccount(senderConcrete.MutableAccount()); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	if _, err := sapps.mgr.state.SetState(map[string][]byte{
+		abiAddr: txn.GetAbiJson(),
+	}); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	return HandlerResult{}
+}
+
+// getRegisteredABI returns the JSON ABI registered for a contract address,
+// or nil if none was registered via RegisterABI.
+func getRegisteredABI(context *processor.Context, contractAddr []byte) ([]byte, error) {
+	abiAddr, err := NewAbiRegistryAddr(contractAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := context.GetState([]string{abiAddr})
+	if err != nil {
+		return nil, err
+	}
+
+	abiJson, exists := entries[abiAddr]
+	if !exists || len(abiJson) == 0 {
+		return nil, nil
+	}
+
+	return abiJson, nil
+}
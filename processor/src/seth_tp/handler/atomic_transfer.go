@@ -0,0 +1,230 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package handler
+
+import (
+	. "common"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/burrow/acm"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/evm"
+	"github.com/hyperledger/burrow/permission"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	. "protobuf/seth_pb2"
+)
+
+// ExportTxn debits a SETH account and records a burn that a peer
+// Sawtooth transaction family, scheduled in the same block, can observe
+// and import from. Both sides must declare the cross-family burn address
+// in their transaction header input/output namespaces for the scheduler
+// to execute them atomically.
+func ExportTxn(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppState) HandlerResult {
+	txn := wrapper.GetExportTxn()
+
+	from, err := NewEvmAddrFromBytes(txn.GetFrom())
+	if err != nil || from.String() != sender.String() {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"ExportTxn From must match the signing account: %v", txn.GetFrom(),
+			)},
+		}
+	}
+
+	senderAcct, err := sapps.GetAccount(crypto.AddressFromWord256(sender.ToWord256()))
+	if senderAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account must already exist to export value: %v", sender,
+			)},
+		}
+	}
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
+	if txn.GetAmount() > senderConcrete.Balance {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Insufficient balance to export %v from %v", txn.GetAmount(), sender,
+			)},
+		}
+	}
+
+	senderConcrete.Balance -= txn.GetAmount()
+	senderConcrete.Sequence += 1
+	if err := sapps.UpdateAccount(senderConcrete.MutableAccount()); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	burnAddr, err := NewCrossFamilyBurnAddr(txn.GetDestNamespace(), txn.GetCorrelationId())
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+			"Failed to construct cross-family burn address: %v", err,
+		)}}
+	}
+
+	record := &CrossFamilyBurnRecord{
+		From:        txn.GetFrom(),
+		Amount:      txn.GetAmount(),
+		DestPayload: txn.GetDestPayload(),
+		Consumed:    false,
+	}
+	recordBytes, err := proto.Marshal(record)
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	if _, err := sapps.mgr.state.SetState(map[string][]byte{
+		burnAddr: recordBytes,
+	}); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	sapps.mgr.state.AddEvent("seth_atomic_event", []processor.Attribute{
+		{Key: "direction", Value: "export"},
+		{Key: "destFamily", Value: txn.GetDestNamespace()},
+		{Key: "correlationId", Value: txn.GetCorrelationId()},
+	}, recordBytes)
+
+	return HandlerResult{}
+}
+
+// ImportTxn credits a SETH account, but only once it finds a matching,
+// not-yet-consumed burn record a peer family produced earlier in or
+// before this block. Importing is a privileged, relayer-only action:
+// nothing in this family can verify the peer family's proof of burn on
+// its own, so the only thing standing between "relay a genuine burn" and
+// "mint SETH out of thin air" is that the importer is trusted (gated on
+// permission.Root, the same bar SetPermissions itself uses) and that a
+// given burn record can only ever be imported once.
+func ImportTxn(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppState) HandlerResult {
+	txn := wrapper.GetImportTxn()
+
+	senderAcct, err := sapps.GetAccount(crypto.AddressFromWord256(sender.ToWord256()))
+	if senderAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account must already exist to import value: %v", sender,
+			)},
+		}
+	}
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
+	if !evm.HasPermission(sapps, senderConcrete.Address, permission.Root) {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account does not have permission to import cross-family value: %v",
+				sender,
+			)},
+		}
+	}
+	if txn.GetNonce() != senderConcrete.Sequence {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Nonces do not match: Transaction (%v), State (%v)",
+				txn.GetNonce(), senderConcrete.Sequence,
+			)},
+		}
+	}
+
+	to, err := NewEvmAddrFromBytes(txn.GetTo())
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Failed to construct destination address for import: %v", txn.GetTo(),
+			)},
+		}
+	}
+
+	burnAddr, err := NewCrossFamilyBurnAddr(txn.GetSourceNamespace(), txn.GetCorrelationId())
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+			"Failed to construct cross-family burn address: %v", err,
+		)}}
+	}
+
+	entries, err := sapps.mgr.state.GetState([]string{burnAddr})
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+	recordBytes, exists := entries[burnAddr]
+	if !exists || len(recordBytes) == 0 {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+			"No matching burn record at %v; cannot import before export is visible", burnAddr,
+		)}}
+	}
+
+	record := &CrossFamilyBurnRecord{}
+	if err := proto.Unmarshal(recordBytes, record); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+	if record.GetConsumed() {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+			"Burn record at %v was already imported", burnAddr,
+		)}}
+	}
+	if record.GetAmount() != txn.GetAmount() {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+			"Import amount %v does not match burn record amount %v", txn.GetAmount(), record.GetAmount(),
+		)}}
+	}
+
+	toAddr := crypto.AddressFromWord256(to.ToWord256())
+	toAcct, err := sapps.GetAccount(toAddr)
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+	if toAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Destination account must already exist to import value: %v", to,
+			)},
+		}
+	}
+	toConcrete := acm.AsConcreteAccount(toAcct)
+
+	// Tombstone the burn record in the same write that credits toAcct, so
+	// a second ImportTxn against this record - whether replayed by the
+	// original relayer or submitted by whoever originally exported it -
+	// can never mint SETH a second time.
+	record.Consumed = true
+	consumedBytes, err := proto.Marshal(record)
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+	if _, err := sapps.mgr.state.SetState(map[string][]byte{
+		burnAddr: consumedBytes,
+	}); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	senderConcrete.Sequence += 1
+	if err := sapps.UpdateAccount(senderConcrete.MutableAccount()); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	toConcrete.Balance += txn.GetAmount()
+	if err := sapps.UpdateAccount(toConcrete.MutableAccount()); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	sapps.mgr.state.AddEvent("seth_atomic_event", []processor.Attribute{
+		{Key: "direction", Value: "import"},
+		{Key: "sourceFamily", Value: txn.GetSourceNamespace()},
+		{Key: "correlationId", Value: txn.GetCorrelationId()},
+	}, consumedBytes)
+
+	return HandlerResult{}
+}
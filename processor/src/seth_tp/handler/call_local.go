@@ -0,0 +1,110 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package handler
+
+import (
+	. "common"
+	"fmt"
+	"github.com/hyperledger/burrow/acm"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/evm"
+	"github.com/hyperledger/burrow/permission"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	. "protobuf/seth_pb2"
+)
+
+// CallLocal executes a message call the same way MessageCall does, but
+// never touches the sender's nonce or either side's balance: like
+// eth_call, it reports what a MESSAGE_CALL would return without being a
+// transaction against the ledger. It has no nonce to check, since it
+// isn't one. Any storage writes made while running the call are taken
+// against a snapshot of global state and discarded afterwards, the same
+// way PendingCallContract/EstimateGas do in the simulated backend, so
+// CallLocal is safe to point at bytecode that isn't known to be
+// read-only.
+func CallLocal(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppState) HandlerResult {
+	txn := wrapper.GetCallLocal()
+
+	senderAcct, err := sapps.GetAccount(crypto.AddressFromWord256(sender.ToWord256()))
+	if senderAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account must already exist to make a local call: %v", sender,
+			)},
+		}
+	}
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
+
+	if !evm.HasPermission(sapps, senderConcrete.Address, permission.Call) {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account does not have permission to make message calls: %v",
+				sender,
+			)},
+		}
+	}
+
+	receiver, err := NewEvmAddrFromBytes(txn.GetTo())
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Failed to construct receiver address for local call: %v", txn.GetTo(),
+			)},
+		}
+	}
+
+	receiverAcct, err := sapps.GetAccount(crypto.AddressFromWord256(receiver.ToWord256()))
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Error while retrieving receiver account: %v", err,
+			)},
+		}
+	}
+	if receiverAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Receiver account must already exist to call it: %v", receiver,
+			)},
+		}
+	}
+	receiverConcrete := acm.AsConcreteAccount(receiverAcct)
+
+	snapshot := SnapshotContext(sapps.mgr.state)
+	defer RestoreContext(sapps.mgr.state, snapshot)
+
+	out, gasUsed, err := callVm(
+		sapps,
+		senderConcrete.MutableAccount(),
+		receiverConcrete.MutableAccount(),
+		receiverConcrete.Code.Bytes(),
+		txn.GetData(),
+		0,
+		txn.GetGasLimit(),
+	)
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: err.Error()},
+		}
+	}
+
+	return HandlerResult{
+		ReturnValue: out,
+		GasUsed:     gasUsed,
+	}
+}
@@ -0,0 +1,244 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package handler
+
+import (
+	"common"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/burrow/acm"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/setting_pb2"
+	"strconv"
+	"strings"
+)
+
+// FeeCollectorAddress accumulates gasPrice*gasUsed from every priced
+// transaction, the way a block's coinbase would in a chain that has one.
+// Nothing drains it yet; it exists so fee volume is visible in state
+// rather than simply burned.
+var FeeCollectorAddress = reservedAddress(11)
+
+// minGasPriceSetting is the Sawtooth settings key operators use to
+// configure the lowest gasPrice this family will accept. Unset (the
+// common case), it imposes no floor.
+const minGasPriceSetting = "seth.gas_price.minimum"
+
+// settingsNamespace is the fixed address prefix the Sawtooth settings
+// transaction family stores every setting under.
+const settingsNamespace = "000000"
+
+// settingsAddress reproduces the settings family's own addressing
+// scheme, so this family can read a setting it doesn't own without
+// depending on that family's code: the key is split into at most 4
+// dot-separated parts, and each part (padding with empty parts as
+// needed) contributes a 16-hex-character SHA-256 prefix to the address.
+func settingsAddress(key string) string {
+	parts := strings.SplitN(key, ".", 4)
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+
+	addr := settingsNamespace
+	for _, part := range parts {
+		sum := sha256.Sum256([]byte(part))
+		addr += hex.EncodeToString(sum[:])[:16]
+	}
+	return addr
+}
+
+// minGasPrice reads minGasPriceSetting from on-chain settings, returning
+// 0 (no floor) if it has never been set.
+func minGasPrice(sapps *SawtoothAppState) (uint64, error) {
+	addr := settingsAddress(minGasPriceSetting)
+
+	entries, err := sapps.mgr.state.GetState([]string{addr})
+	if err != nil {
+		return 0, err
+	}
+	raw, exists := entries[addr]
+	if !exists || len(raw) == 0 {
+		return 0, nil
+	}
+
+	setting := &setting_pb2.Setting{}
+	if err := proto.Unmarshal(raw, setting); err != nil {
+		return 0, fmt.Errorf("Malformed settings entry at %v: %v", addr, err)
+	}
+	for _, entry := range setting.GetEntries() {
+		if entry.GetKey() != minGasPriceSetting {
+			continue
+		}
+		floor, err := strconv.ParseUint(strings.TrimSpace(entry.GetValue()), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf(
+				"Malformed %v setting value %q: %v", minGasPriceSetting, entry.GetValue(), err,
+			)
+		}
+		return floor, nil
+	}
+
+	return 0, nil
+}
+
+// chargeGas reserves gasPrice*gasLimit from senderAcct, runs execute with
+// the full gasLimit, then refunds whatever execute didn't spend and
+// credits FeeCollectorAddress with what it did. It also debits the
+// block's shared gas pool, so a block can't admit more total gas than
+// common.GAS_LIMIT regardless of how many transactions ask for it.
+// senderAcct is mutated in place; the caller still owns calling
+// UpdateAccount on it afterwards, the same as it already does for nonce
+// and value changes.
+//
+// execute failing is reported back via reverted rather than a non-nil
+// err: Apply discards every state write a handler makes when it returns
+// an error, so the only way the fee-forfeiture credit below actually
+// survives a revert is for chargeGas itself to return successfully. A
+// non-nil err out of chargeGas is reserved for genuine infrastructure
+// failures (a bad setting, a state read/write error) that should still
+// abort the whole transaction; callers should treat reverted as "the
+// call didn't take its intended effect" without aborting.
+func chargeGas(sapps *SawtoothAppState, senderAcct acm.Account, gasPrice, gasLimit uint64,
+	execute func(gas uint64) ([]byte, uint64, error)) (out []byte, gasUsed uint64, reverted bool, err error) {
+
+	floor, err := minGasPrice(sapps)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("Failed to read %v setting: %v", minGasPriceSetting, err)
+	}
+	if gasPrice < floor {
+		return nil, 0, false, fmt.Errorf(
+			"gasPrice %v is below the network floor %v", gasPrice, floor,
+		)
+	}
+
+	if err := reserveBlockGas(sapps, gasLimit); err != nil {
+		return nil, 0, false, err
+	}
+
+	concrete := acm.AsConcreteAccount(senderAcct)
+	fee := gasPrice * gasLimit
+	if fee > 0 {
+		if fee > concrete.Balance {
+			releaseBlockGas(sapps, gasLimit)
+			return nil, 0, false, fmt.Errorf(
+				"Insufficient balance to cover gas: need %v, have %v", fee, concrete.Balance,
+			)
+		}
+		concrete.Balance -= fee
+	}
+
+	out, execGasUsed, execErr := execute(gasLimit)
+
+	if execErr != nil {
+		// The call didn't run to completion, so gasUsed can't be trusted
+		// to reflect how much work was actually done. Retain the whole
+		// prepaid fee instead of refunding it: no credit back to
+		// senderAcct, and no release of the block gas pool reservation,
+		// so a reverting call can't be used to grief either for free.
+		if gasPrice > 0 {
+			if cErr := creditFeeCollector(sapps, fee); cErr != nil {
+				return out, gasLimit, true, cErr
+			}
+		}
+		return out, gasLimit, true, nil
+	}
+
+	releaseBlockGas(sapps, gasLimit-execGasUsed)
+
+	if gasPrice > 0 {
+		concrete.Balance += gasPrice * (gasLimit - execGasUsed)
+		if cErr := creditFeeCollector(sapps, gasPrice*execGasUsed); cErr != nil {
+			return out, execGasUsed, false, cErr
+		}
+	}
+
+	return out, execGasUsed, false, nil
+}
+
+// creditFeeCollector adds amount to FeeCollectorAddress's balance,
+// creating the account if this is the first fee it has ever collected.
+func creditFeeCollector(sapps *SawtoothAppState, amount uint64) error {
+	collector, err := sapps.GetAccount(FeeCollectorAddress)
+	if err != nil {
+		return err
+	}
+	if collector == nil {
+		collector = acm.ConcreteAccount{Address: FeeCollectorAddress}.MutableAccount()
+	}
+	concrete := acm.AsConcreteAccount(collector)
+	concrete.Balance += amount
+	return sapps.UpdateAccount(concrete.MutableAccount())
+}
+
+// reserveBlockGas debits amount from the current block's gas pool,
+// failing if doing so would push it below zero.
+func reserveBlockGas(sapps *SawtoothAppState, amount uint64) error {
+	remaining, poolAddr, err := blockGasPoolRemaining(sapps)
+	if err != nil {
+		return err
+	}
+	if amount > remaining {
+		return fmt.Errorf(
+			"Block gas pool exhausted: requested %v, %v remaining", amount, remaining,
+		)
+	}
+	return setBlockGasPoolRemaining(sapps, poolAddr, remaining-amount)
+}
+
+// releaseBlockGas credits amount back to the current block's gas pool, to
+// return gas that was reserved up front but never spent.
+func releaseBlockGas(sapps *SawtoothAppState, amount uint64) error {
+	remaining, poolAddr, err := blockGasPoolRemaining(sapps)
+	if err != nil {
+		return err
+	}
+	return setBlockGasPoolRemaining(sapps, poolAddr, remaining+amount)
+}
+
+func blockGasPoolRemaining(sapps *SawtoothAppState) (uint64, string, error) {
+	params, err := getParams(sapps.mgr.state)
+	if err != nil {
+		return 0, "", err
+	}
+
+	poolAddr, err := common.NewGasPoolAddr(int64(params.BlockHeight))
+	if err != nil {
+		return 0, "", fmt.Errorf("Failed to construct block gas pool address: %v", err)
+	}
+
+	entries, err := sapps.mgr.state.GetState([]string{poolAddr})
+	if err != nil {
+		return 0, "", err
+	}
+
+	remaining := common.GAS_LIMIT
+	if raw, exists := entries[poolAddr]; exists && len(raw) == 8 {
+		remaining = binary.BigEndian.Uint64(raw)
+	}
+
+	return remaining, poolAddr, nil
+}
+
+func setBlockGasPoolRemaining(sapps *SawtoothAppState, poolAddr string, remaining uint64) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, remaining)
+	_, err := sapps.mgr.state.SetState(map[string][]byte{poolAddr: buf})
+	return err
+}
@@ -38,8 +38,9 @@ import (
 
 type HandlerResult struct {
 	GasUsed     uint64
+	GasRefunded uint64
 	ReturnValue []byte
-	NewAccount  *acm.Account
+	NewAccount  acm.Account
 	Error       error
 }
 
@@ -48,12 +49,24 @@ type TransactionHandler func(*SethTransaction, *common.EvmAddr, *SawtoothAppStat
 var logger *slogging.Logger = slogging.Get()
 var vm_logger = logging.NewNoopLogger()
 
-type BurrowEVMHandler struct{}
+type BurrowEVMHandler struct {
+	// localPublicKey identifies this validator to the private transaction
+	// path: a PrivateTxn is only decrypted and executed locally when this
+	// key appears among the transaction's recipient public keys.
+	localPublicKey string
+}
 
 func NewBurrowEVMHandler() *BurrowEVMHandler {
 	return &BurrowEVMHandler{}
 }
 
+// NewBurrowEVMHandlerWithIdentity builds a handler that additionally knows
+// its own public key, so it can determine whether it is a party to a
+// PrivateTxn.
+func NewBurrowEVMHandlerWithIdentity(localPublicKey string) *BurrowEVMHandler {
+	return &BurrowEVMHandler{localPublicKey: localPublicKey}
+}
+
 func (self *BurrowEVMHandler) FamilyName() string {
 	return common.FAMILY_NAME
 }
@@ -105,6 +118,7 @@ func (self *BurrowEVMHandler) Apply(request *processor_pb2.TpProcessRequest, con
 
 	// Construct new state manager
 	sapps := NewSawtoothAppState(context)
+	sapps.localPublicKey = self.localPublicKey
 
 	// Ensure that the global permissions are set
 	receiverAcct, err := sapps.GetAccount(acm.GlobalPermissionsAddress)
@@ -114,11 +128,10 @@ func (self *BurrowEVMHandler) Apply(request *processor_pb2.TpProcessRequest, con
 	if receiverAcct == nil {
 		perms := permission.AllAccountPermissions
 		perms.Base.SetBit = permission.AllPermFlags
-		permsAcc := &acm.Account{
+		permsAcc := acm.ConcreteAccount{
 			Address:     acm.GlobalPermissionsAddress,
-			Balance:     0,
 			Permissions: perms,
-		}
+		}.MutableAccount()
 
 		err = sapps.UpdateAccount(permsAcc)
 		if err != nil {
@@ -126,6 +139,19 @@ func (self *BurrowEVMHandler) Apply(request *processor_pb2.TpProcessRequest, con
 		}
 	}
 
+	// Ensure the SNative permissions contract account exists, so it can be
+	// targeted by a MessageCall like any other contract.
+	snativeAcct, err := sapps.GetAccount(SNativePermissionsAddress)
+	if err != nil {
+		return err
+	}
+	if snativeAcct == nil {
+		err = sapps.UpdateAccount(acm.ConcreteAccount{Address: SNativePermissionsAddress}.MutableAccount())
+		if err != nil {
+			return err
+		}
+	}
+
 	// Call the handler
 	result := handler(wrapper, sender, sapps)
 	if result.Error != nil {
@@ -134,12 +160,13 @@ func (self *BurrowEVMHandler) Apply(request *processor_pb2.TpProcessRequest, con
 
 	var contractAddress []byte
 	if result.NewAccount != nil {
-		contractAddress = result.NewAccount.Address.Bytes()
+		contractAddress = acm.AsConcreteAccount(result.NewAccount).Address.Bytes()
 	}
 
 	receipt := &SethTransactionReceipt{
 		ContractAddress: contractAddress,
 		GasUsed:         result.GasUsed,
+		GasRefunded:     result.GasRefunded,
 		ReturnValue:     result.ReturnValue,
 	}
 
@@ -162,14 +189,14 @@ func (self *BurrowEVMHandler) Apply(request *processor_pb2.TpProcessRequest, con
 
 // -- utilities --
 
-func callVm(sas *SawtoothAppState, sender, receiver *acm.Account,
-	code, input []byte, gas uint64) ([]byte, uint64, error) {
+func callVm(sas *SawtoothAppState, sender, receiver acm.Account,
+	code, input []byte, value, gas uint64) ([]byte, uint64, error) {
 	// Create EVM
 	params, err := getParams(sas.mgr.state)
 	if err != nil {
 		return nil, 0, fmt.Errorf("Block Info Error: %v", err)
 	}
-	vm := evm.NewVM(*params, crypto.ZeroAddress, nil, vm_logger)
+	vm := evm.NewVM(*params, crypto.ZeroAddress, nil, vm_logger, evm.Natives(snativeNatives(sas)))
 	evc := NewSawtoothEventFireable(sas.mgr.state)
 
 	// Convert the gas to a signed int to be compatible with the burrow EVM
@@ -179,8 +206,24 @@ func callVm(sas *SawtoothAppState, sender, receiver *acm.Account,
 	if receiver == nil {
 		receiver = sender
 	}
+	receiverAddr := acm.AsConcreteAccount(receiver).Address
+
+	// This top-level check is redundant with registering the Permissions
+	// contract as a VM native above - both end up at
+	// dispatchSNativePermissions - but it's cheap to keep and avoids
+	// spinning up the interpreter at all for the common case of a direct
+	// call to the permissions contract.
+	if receiverAddr == SNativePermissionsAddress {
+		return dispatchSNativePermissions(sas, sender, input, gas)
+	}
+
+	code, err = sas.ResolveCode(code)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to resolve delegated code: %v", err)
+	}
 
-	output, err := vm.Call(sas, evc, sender.Address, receiver.Address, code, input, 0, &endGas)
+	senderAddr := acm.AsConcreteAccount(sender).Address
+	output, err := vm.Call(sas, evc, senderAddr, receiverAddr, code, input, value, &endGas)
 	if err != nil {
 		return nil, 0, fmt.Errorf("EVM Error: %v", err)
 	}
@@ -0,0 +1,267 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package handler
+
+import (
+	. "common"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/burrow/acm"
+	"github.com/hyperledger/burrow/binary"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	. "protobuf/seth_pb2"
+	"io/ioutil"
+	"net/http"
+)
+
+// PrivateStorageNonce is the pseudo-nonce used to derive the address of an
+// account's private state counterpart, keeping private storage in a
+// namespace that can never collide with a contract address created through
+// normal CreateContractAccount/Derive calls.
+const PrivateStorageNonce uint64 = 0xffffffffffffffff
+
+// privateTouchedKey is the well-known storage slot set on an account's
+// public record to mark that it was the target of a PrivateTxn, without
+// revealing whether this validator was able to execute it.
+var privateTouchedKey = binary.RightPadWord256([]byte("seth:privately-touched"))
+
+// payloadManager fetches the plaintext payload for a PrivateTxn given the
+// hash that was placed on-chain. The real payload never touches the
+// ledger; only parties to the transaction are expected to be able to
+// resolve it.
+type payloadManager interface {
+	Fetch(payloadHash []byte) ([]byte, error)
+}
+
+// httpPayloadManager resolves payloads from an off-chain payload manager
+// reachable over HTTP, addressed as <baseURL>/<hex(payloadHash)>.
+type httpPayloadManager struct {
+	baseURL string
+}
+
+func (p *httpPayloadManager) Fetch(payloadHash []byte) ([]byte, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/%s", p.baseURL, hex.EncodeToString(payloadHash)))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to reach payload manager: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Payload manager returned status %v", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// PrivateTxn executes a Quorum-style confidential contract transaction.
+// Only the payload hash and recipient public keys are ever written to
+// public state; the real code, call data, and resulting storage stay in a
+// private namespace that non-party validators never populate.
+func PrivateTxn(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppState) HandlerResult {
+	txn := wrapper.GetPrivateTxn()
+
+	senderAcct, err := sapps.GetAccount(crypto.AddressFromWord256(sender.ToWord256()))
+	if senderAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account must already exist to submit a private transaction: %v", sender,
+			)},
+		}
+	}
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
+	if txn.GetNonce() != senderConcrete.Sequence {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Nonces do not match: Transaction (%v), State (%v)",
+				txn.GetNonce(), senderConcrete.Sequence,
+			)},
+		}
+	}
+
+	receiver, err := NewEvmAddrFromBytes(txn.GetTo())
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Failed to construct receiver address for private transaction: %v", txn.GetTo(),
+			)},
+		}
+	}
+	receiverAddr := crypto.AddressFromWord256(receiver.ToWord256())
+	receiverAcct, err := sapps.GetAccount(receiverAddr)
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Error while retrieving receiver account: %v", err,
+			)},
+		}
+	}
+	if receiverAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Receiver account must already exist to call it: %v", receiver,
+			)},
+		}
+	}
+
+	// The sender's nonce and the receiver's "privately touched" marker
+	// always advance deterministically, whether or not this validator is
+	// a party to the transaction, so that a public contract calling into
+	// a private one sees a consistent zero-value transfer everywhere.
+	senderConcrete.Sequence += 1
+	if err := sapps.SetStorage(receiverAddr, privateTouchedKey, binary.One256); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	var result HandlerResult
+	if sapps.isPrivateParty(txn.GetRecipientPublicKeys()) {
+		result = sapps.executePrivateCall(senderConcrete.MutableAccount(), receiverAddr, txn)
+		if result.Error != nil {
+			return result
+		}
+	}
+
+	if err := sapps.UpdateAccount(senderConcrete.MutableAccount()); err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: err.Error()},
+		}
+	}
+	if err := sapps.UpdateAccount(receiverAcct); err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: err.Error()},
+		}
+	}
+
+	return result
+}
+
+// isPrivateParty reports whether this validator's configured identity
+// appears among a PrivateTxn's recipient public keys.
+func (s *SawtoothAppState) isPrivateParty(recipientKeys []string) bool {
+	if s.localPublicKey == "" {
+		return false
+	}
+	for _, key := range recipientKeys {
+		if key == s.localPublicKey {
+			return true
+		}
+	}
+	return false
+}
+
+// executePrivateCall fetches the real payload from the configured payload
+// manager and runs it through callVm against this account's private state
+// counterpart, so results never leak onto the public state tree. Gas is
+// still metered and charged against senderAcct, the same as a public call.
+func (s *SawtoothAppState) executePrivateCall(senderAcct acm.Account, receiverAddr crypto.Address, txn *PrivateTxn) HandlerResult {
+	config, err := getPrivatePayloadManagerConfig(s.mgr.state)
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+			"Private payload manager not configured: %v", err,
+		)}}
+	}
+
+	manager := &httpPayloadManager{baseURL: config.GetUrl()}
+	payloadBytes, err := manager.Fetch(txn.GetPayloadHash())
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+			"Failed to fetch private payload: %v", err,
+		)}}
+	}
+
+	payload := &PrivateTxnPayload{}
+	if err := json.Unmarshal(payloadBytes, payload); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+			"Malformed private payload: %v", err,
+		)}}
+	}
+
+	privateReceiver, err := s.getPrivateAccount(receiverAddr)
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+	privateConcrete := acm.AsConcreteAccount(privateReceiver)
+	if privateConcrete.Code == nil {
+		privateConcrete.Code = payload.GetCode()
+	}
+
+	// Route the call through chargeGas like every other priced transaction,
+	// rather than calling callVm directly: doing so would let a private
+	// call dodge the gas-price floor, the sender's balance debit, and the
+	// block gas pool reservation that public calls enforce.
+	gasPrice := txn.GetGasPrice()
+	gasLimit := txn.GetGasLimit()
+	out, gasUsed, _, err := chargeGas(s, senderAcct, gasPrice, gasLimit, func(gas uint64) ([]byte, uint64, error) {
+		return callVm(
+			s, privateConcrete.MutableAccount(), privateConcrete.MutableAccount(), privateConcrete.Code.Bytes(), payload.GetData(), 0, gas,
+		)
+	})
+	if err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	if err := s.UpdateAccount(privateConcrete.MutableAccount()); err != nil {
+		return HandlerResult{Error: &processor.InvalidTransactionError{Msg: err.Error()}}
+	}
+
+	return HandlerResult{
+		GasUsed:     gasUsed,
+		GasRefunded: gasPrice * (gasLimit - gasUsed),
+		ReturnValue: out,
+	}
+}
+
+// getPrivateAccount resolves the private state counterpart of a public
+// address, creating it on first use.
+func (s *SawtoothAppState) getPrivateAccount(addr crypto.Address) (acm.Account, error) {
+	vmAddr, err := NewEvmAddrFromBytes(addr.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	privateAddr := vmAddr.Derive(PrivateStorageNonce)
+	privateWord256 := crypto.AddressFromWord256(privateAddr.ToWord256())
+
+	acct, err := s.GetAccount(privateWord256)
+	if err != nil {
+		return nil, err
+	}
+	if acct == nil {
+		acct = acm.ConcreteAccount{Address: privateWord256}.MutableAccount()
+	}
+	return acct, nil
+}
+
+func getPrivatePayloadManagerConfig(context *processor.Context) (*PrivatePayloadManagerConfig, error) {
+	entries, err := context.GetState([]string{PRIVATE_CONFIG_ADDRESS})
+	if err != nil {
+		return nil, err
+	}
+	entryData, exists := entries[PRIVATE_CONFIG_ADDRESS]
+	if !exists || len(entryData) == 0 {
+		return nil, fmt.Errorf("Private payload manager config does not exist")
+	}
+
+	config := &PrivatePayloadManagerConfig{}
+	if err := proto.Unmarshal(entryData, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
@@ -19,6 +19,7 @@ package handler
 
 import (
 	. "common"
+	"bytes"
 	"fmt"
 	"github.com/hyperledger/burrow/acm"
 	"github.com/hyperledger/burrow/binary"
@@ -34,6 +35,10 @@ import (
 // access global state
 type SawtoothAppState struct {
 	mgr *StateManager
+
+	// localPublicKey is the identity of the validator running this handler,
+	// used to decide whether a PrivateTxn should be executed locally.
+	localPublicKey string
 }
 
 func NewSawtoothAppState(state *processor.Context) *SawtoothAppState {
@@ -60,7 +65,40 @@ func (s *SawtoothAppState) GetAccount(addr crypto.Address) (acm.Account, error)
 		return nil, nil
 	}
 
-	return toVmAccount(entry.GetAccount()), nil
+	stateAcct := entry.GetAccount()
+	resolved, err := s.ResolveCode(stateAcct.GetCode())
+	if err != nil {
+		return nil, err
+	}
+	stateAcct.Code = resolved
+
+	return toVmAccount(stateAcct), nil
+}
+
+// ResolveCode follows an EIP-7702 delegation designator to the code of the
+// contract it points at, so that the EVM executes the delegate's bytecode
+// while the delegating EOA's storage, balance, and address stay bound to
+// the account being loaded. Code that doesn't carry the designator prefix
+// is returned unchanged.
+func (s *SawtoothAppState) ResolveCode(code []byte) ([]byte, error) {
+	if !bytes.HasPrefix(code, DelegationDesignatorPrefix) {
+		return code, nil
+	}
+
+	delegate, err := NewEvmAddrFromBytes(code[len(DelegationDesignatorPrefix):])
+	if err != nil {
+		return nil, err
+	}
+
+	delegateAcct, err := s.GetAccount(crypto.AddressFromWord256(delegate.ToWord256()))
+	if err != nil {
+		return nil, err
+	}
+	if delegateAcct == nil {
+		return []byte{}, nil
+	}
+
+	return acm.AsConcreteAccount(delegateAcct).Code.Bytes(), nil
 }
 
 // UpdateAccount updates the account in state. Creates the account if it doesn't
@@ -248,6 +286,7 @@ func toStatePermissions(aPerm permission.AccountPermissions) *EvmPermissions {
 	return &EvmPermissions{
 		Perms:  uint64(aPerm.Base.Perms),
 		SetBit: uint64(aPerm.Base.SetBit),
+		Roles:  aPerm.Roles,
 	}
 }
 
@@ -257,5 +296,6 @@ func toVmPermissions(ePerm *EvmPermissions) permission.AccountPermissions {
 			Perms:  permission.PermFlag(ePerm.Perms),
 			SetBit: permission.PermFlag(ePerm.SetBit),
 		},
+		Roles: ePerm.GetRoles(),
 	}
 }
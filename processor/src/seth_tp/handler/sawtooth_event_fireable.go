@@ -18,8 +18,10 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/hyperledger/burrow/execution/errors"
 	"github.com/hyperledger/burrow/execution/exec"
 	"github.com/hyperledger/sawtooth-sdk-go/processor"
@@ -56,7 +58,108 @@ func (evc *SawtoothEventFireable) Log(log *exec.LogEvent) error {
 			Value: hex.EncodeToString(topic.Bytes()),
 		})
 	}
+
+	// Fall back to the raw hex fields above when no ABI is registered for
+	// this contract; decoded attributes are additive, never a replacement.
+	if decoded, err := decodeLogWithABI(evc.context, log); err != nil {
+		logger.Debugf("Failed to decode event with registered ABI: %v", err)
+	} else {
+		attributes = append(attributes, decoded...)
+	}
+
 	evc.context.AddEvent("seth_log_event", attributes, log.Data)
 
 	return nil
 }
+
+// decodeLogWithABI looks up the ABI registered for log.Address and, if one
+// is found, decodes the indexed topics and data blob into named
+// attributes (arg0_name=<decoded>) alongside an eventSignature attribute
+// so subscribers can filter on topic0 == keccak(...) without embedding an
+// ABI decoder themselves.
+func decodeLogWithABI(context *processor.Context, log *exec.LogEvent) ([]processor.Attribute, error) {
+	if len(log.Topics) == 0 {
+		return nil, nil
+	}
+
+	abiJson, err := getRegisteredABI(context, log.Address.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if abiJson == nil {
+		return nil, nil
+	}
+
+	contractABI, err := abi.JSON(bytes.NewReader(abiJson))
+	if err != nil {
+		return nil, fmt.Errorf("Malformed registered ABI: %v", err)
+	}
+
+	var event *abi.Event
+	for _, candidate := range contractABI.Events {
+		if bytes.Equal(candidate.ID.Bytes(), log.Topics[0].Bytes()) {
+			e := candidate
+			event = &e
+			break
+		}
+	}
+	if event == nil {
+		return nil, nil
+	}
+
+	attributes := []processor.Attribute{
+		{Key: "event", Value: event.Name},
+		{Key: "eventSignature", Value: hex.EncodeToString(event.ID.Bytes())},
+	}
+
+	indexedTopic := 1
+	var nonIndexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if !arg.Indexed {
+			nonIndexed = append(nonIndexed, arg)
+			continue
+		}
+		if indexedTopic >= len(log.Topics) {
+			continue
+		}
+		attributes = append(attributes, processor.Attribute{
+			Key:   fmt.Sprintf("arg%v_%v", indexedTopic-1, arg.Name),
+			Value: decodeIndexedTopic(arg.Type, log.Topics[indexedTopic].Bytes()),
+		})
+		indexedTopic += 1
+	}
+
+	values, err := nonIndexed.UnpackValues(log.Data)
+	if err != nil {
+		return attributes, fmt.Errorf("Failed to unpack non-indexed event data: %v", err)
+	}
+	for i, arg := range nonIndexed {
+		if i >= len(values) {
+			break
+		}
+		attributes = append(attributes, processor.Attribute{
+			Key:   fmt.Sprintf("arg%v_%v", i, arg.Name),
+			Value: fmt.Sprintf("%v", values[i]),
+		})
+	}
+
+	return attributes, nil
+}
+
+// decodeIndexedTopic renders a single indexed event argument. Solidity
+// only stores a STATIC indexed argument's value directly in its topic; a
+// DYNAMIC one (string, bytes, or a dynamic-size array) is replaced with
+// keccak256(value) instead, which can't be decoded back into anything
+// meaningful here, so those fall back to raw hex of the topic word.
+func decodeIndexedTopic(argType abi.Type, topic []byte) string {
+	switch argType.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy:
+		return hex.EncodeToString(topic)
+	}
+
+	values, err := (abi.Arguments{{Type: argType}}).UnpackValues(topic)
+	if err != nil || len(values) == 0 {
+		return hex.EncodeToString(topic)
+	}
+	return fmt.Sprintf("%v", values[0])
+}
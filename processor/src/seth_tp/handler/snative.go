@@ -0,0 +1,277 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package handler
+
+import (
+	"fmt"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/hyperledger/burrow/acm"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/evm"
+	"github.com/hyperledger/burrow/permission"
+)
+
+// Addresses 0x01-0x09 are reserved for the EVM's own precompiles
+// (ecrecover and friends); SNativePermissionsAddress is the next one up,
+// 0x0a, and is where the permission snative contract below is callable
+// from. It carries no EVM bytecode of its own: callVm dispatches calls to
+// it directly instead of running them through the interpreter.
+var SNativePermissionsAddress = reservedAddress(10)
+
+// snativeGasCost is charged for every dispatched snative call, the same
+// way the EVM's own precompiles charge a flat price instead of metering
+// interpreted instructions.
+const snativeGasCost = 750
+
+var (
+	snativeAddressType, _ = ethabi.NewType("address", "", nil)
+	snativeUint64Type, _  = ethabi.NewType("uint64", "", nil)
+	snativeBoolType, _    = ethabi.NewType("bool", "", nil)
+	snativeStringType, _  = ethabi.NewType("string", "", nil)
+)
+
+// snativeNatives registers the Permissions contract as a VM native, so
+// that a CALL issued from inside a running contract's bytecode - not
+// just the top-level call callVm itself makes - also reaches
+// dispatchSNativePermissions. Without this, a nested CALL to
+// SNativePermissionsAddress would just find an account with no code and
+// silently no-op instead of running the permission logic.
+func snativeNatives(sas *SawtoothAppState) evm.Natives {
+	return evm.NewNatives(evm.NativeContract{
+		Address: SNativePermissionsAddress,
+		Gas:     snativeGasCost,
+		F: func(caller crypto.Address, input []byte) ([]byte, error) {
+			callerAcct, err := sas.GetAccount(caller)
+			if err != nil {
+				return nil, err
+			}
+			if callerAcct == nil {
+				callerAcct = acm.ConcreteAccount{Address: caller}.MutableAccount()
+			}
+			out, _, err := dispatchSNativePermissions(sas, callerAcct, input, snativeGasCost)
+			return out, err
+		},
+	})
+}
+
+func reservedAddress(n byte) crypto.Address {
+	bs := make([]byte, crypto.AddressLength)
+	bs[len(bs)-1] = n
+	addr, err := crypto.AddressFromBytes(bs)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to construct reserved snative address: %v", err))
+	}
+	return addr
+}
+
+// dispatchSNativePermissions implements the Permissions snative contract:
+// a fixed set of Solidity-ABI-compatible functions, callable like any
+// other contract, that read and write the account permission state
+// callVm would otherwise only expose to SetPermissions transactions.
+func dispatchSNativePermissions(sas *SawtoothAppState, caller acm.Account, input []byte, gas uint64) ([]byte, uint64, error) {
+	if gas < snativeGasCost {
+		return nil, 0, fmt.Errorf("Out of gas calling SNative permissions contract")
+	}
+	if len(input) < 4 {
+		return nil, 0, fmt.Errorf("SNative call data too short to contain a function selector")
+	}
+	selector := hex4(input[:4])
+	args := input[4:]
+
+	var out []byte
+	var err error
+	switch selector {
+	case "225b6574": // hasBase(address,uint64)
+		out, err = snativeHasBase(sas, args)
+	case "dbd4a8ea": // setBase(address,uint64,bool)
+		out, err = snativeSetBase(sas, caller, args)
+	case "b7d4dc0d": // unsetBase(address,uint64)
+		out, err = snativeUnsetBase(sas, caller, args)
+	case "c4bc7b70": // setGlobal(uint64,bool)
+		out, err = snativeSetGlobal(sas, caller, args)
+	case "217fe6c6": // hasRole(address,string)
+		out, err = snativeHasRole(sas, args)
+	case "7d72aa65": // addRole(address,string)
+		out, err = snativeChangeRole(sas, caller, args, true)
+	case "1bfe0308": // removeRole(address,string)
+		out, err = snativeChangeRole(sas, caller, args, false)
+	default:
+		return nil, 0, fmt.Errorf("Unknown SNative selector: %v", selector)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, snativeGasCost, nil
+}
+
+func hex4(b []byte) string {
+	const hexDigits = "0123456789abcdef"
+	out := make([]byte, 8)
+	for i, c := range b {
+		out[i*2] = hexDigits[c>>4]
+		out[i*2+1] = hexDigits[c&0x0f]
+	}
+	return string(out)
+}
+
+func requireRoot(sas *SawtoothAppState, caller acm.Account) error {
+	callerAddr := acm.AsConcreteAccount(caller).Address
+	if !evm.HasPermission(sas, callerAddr, permission.Root) {
+		return fmt.Errorf("Caller does not have permission to change permissions: %v", callerAddr)
+	}
+	return nil
+}
+
+func snativeTargetAccount(sas *SawtoothAppState, target ethcommon.Address) (acm.Account, error) {
+	acct, err := sas.GetAccount(crypto.Address(target))
+	if err != nil {
+		return nil, err
+	}
+	if acct == nil {
+		return nil, fmt.Errorf("SNative target account does not exist: %v", target.Hex())
+	}
+	return acct, nil
+}
+
+func snativeHasBase(sas *SawtoothAppState, args []byte) ([]byte, error) {
+	values, err := (ethabi.Arguments{{Type: snativeAddressType}, {Type: snativeUint64Type}}).UnpackValues(args)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode hasBase arguments: %v", err)
+	}
+	target := values[0].(ethcommon.Address)
+	flag := values[1].(uint64)
+
+	has := evm.HasPermission(sas, crypto.Address(target), permission.PermFlag(flag))
+	return (ethabi.Arguments{{Type: snativeBoolType}}).Pack(has)
+}
+
+func snativeSetBase(sas *SawtoothAppState, caller acm.Account, args []byte) ([]byte, error) {
+	if err := requireRoot(sas, caller); err != nil {
+		return nil, err
+	}
+	values, err := (ethabi.Arguments{{Type: snativeAddressType}, {Type: snativeUint64Type}, {Type: snativeBoolType}}).UnpackValues(args)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode setBase arguments: %v", err)
+	}
+	target := values[0].(ethcommon.Address)
+	flag := values[1].(uint64)
+	value := values[2].(bool)
+
+	acct, err := snativeTargetAccount(sas, target)
+	if err != nil {
+		return nil, err
+	}
+	concrete := acm.AsConcreteAccount(acct)
+	concrete.Permissions.Base.Set(permission.PermFlag(flag), value)
+	return nil, sas.UpdateAccount(concrete.MutableAccount())
+}
+
+func snativeUnsetBase(sas *SawtoothAppState, caller acm.Account, args []byte) ([]byte, error) {
+	if err := requireRoot(sas, caller); err != nil {
+		return nil, err
+	}
+	values, err := (ethabi.Arguments{{Type: snativeAddressType}, {Type: snativeUint64Type}}).UnpackValues(args)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode unsetBase arguments: %v", err)
+	}
+	target := values[0].(ethcommon.Address)
+	flag := values[1].(uint64)
+
+	acct, err := snativeTargetAccount(sas, target)
+	if err != nil {
+		return nil, err
+	}
+	concrete := acm.AsConcreteAccount(acct)
+	concrete.Permissions.Base.Unset(permission.PermFlag(flag))
+	return nil, sas.UpdateAccount(concrete.MutableAccount())
+}
+
+func snativeSetGlobal(sas *SawtoothAppState, caller acm.Account, args []byte) ([]byte, error) {
+	if err := requireRoot(sas, caller); err != nil {
+		return nil, err
+	}
+	values, err := (ethabi.Arguments{{Type: snativeUint64Type}, {Type: snativeBoolType}}).UnpackValues(args)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode setGlobal arguments: %v", err)
+	}
+	flag := values[0].(uint64)
+	value := values[1].(bool)
+
+	global, err := sas.GetAccount(acm.GlobalPermissionsAddress)
+	if err != nil {
+		return nil, err
+	}
+	if global == nil {
+		return nil, fmt.Errorf("Global permissions account does not exist")
+	}
+	concrete := acm.AsConcreteAccount(global)
+	concrete.Permissions.Base.Set(permission.PermFlag(flag), value)
+	return nil, sas.UpdateAccount(concrete.MutableAccount())
+}
+
+func snativeHasRole(sas *SawtoothAppState, args []byte) ([]byte, error) {
+	values, err := (ethabi.Arguments{{Type: snativeAddressType}, {Type: snativeStringType}}).UnpackValues(args)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode hasRole arguments: %v", err)
+	}
+	target := values[0].(ethcommon.Address)
+	role := values[1].(string)
+
+	acct, err := snativeTargetAccount(sas, target)
+	if err != nil {
+		return nil, err
+	}
+
+	has := false
+	for _, r := range acm.AsConcreteAccount(acct).Permissions.Roles {
+		if r == role {
+			has = true
+			break
+		}
+	}
+	return (ethabi.Arguments{{Type: snativeBoolType}}).Pack(has)
+}
+
+func snativeChangeRole(sas *SawtoothAppState, caller acm.Account, args []byte, grant bool) ([]byte, error) {
+	if err := requireRoot(sas, caller); err != nil {
+		return nil, err
+	}
+	values, err := (ethabi.Arguments{{Type: snativeAddressType}, {Type: snativeStringType}}).UnpackValues(args)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode role arguments: %v", err)
+	}
+	target := values[0].(ethcommon.Address)
+	role := values[1].(string)
+
+	acct, err := snativeTargetAccount(sas, target)
+	if err != nil {
+		return nil, err
+	}
+
+	concrete := acm.AsConcreteAccount(acct)
+	if grant {
+		concrete.Permissions.Roles = addRole(concrete.Permissions.Roles, role)
+	} else {
+		concrete.Permissions.Roles = removeRole(concrete.Permissions.Roles, role)
+	}
+	if err := sas.UpdateAccount(concrete.MutableAccount()); err != nil {
+		return nil, err
+	}
+	return (ethabi.Arguments{{Type: snativeBoolType}}).Pack(true)
+}
@@ -0,0 +1,220 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package handler
+
+import (
+	. "common"
+	"encoding/hex"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/hyperledger/burrow/acm"
+	"github.com/hyperledger/burrow/permission"
+	"testing"
+)
+
+func TestRequireRootRejectsAccountWithoutRoot(t *testing.T) {
+	sas := newSNativeTestState(t)
+	caller := acm.ConcreteAccount{
+		Address: reservedAddress(200),
+		Permissions: permission.AccountPermissions{
+			Base: permission.BasePermissions{SetBit: permission.Root},
+		},
+	}.MutableAccount()
+	if err := sas.UpdateAccount(caller); err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	if err := requireRoot(sas, caller); err == nil {
+		t.Fatal("expected an account without Root permission to be rejected")
+	}
+}
+
+func TestRequireRootAcceptsAccountWithRoot(t *testing.T) {
+	sas := newSNativeTestState(t)
+	caller := acm.ConcreteAccount{
+		Address: reservedAddress(201),
+		Permissions: permission.AccountPermissions{
+			Base: permission.BasePermissions{
+				Perms:  permission.Root,
+				SetBit: permission.Root,
+			},
+		},
+	}.MutableAccount()
+	if err := sas.UpdateAccount(caller); err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	if err := requireRoot(sas, caller); err != nil {
+		t.Fatalf("expected an account with Root permission to be accepted, got: %v", err)
+	}
+}
+
+func TestAddRoleIsIdempotent(t *testing.T) {
+	roles := addRole(nil, "admin")
+	roles = addRole(roles, "admin")
+
+	if len(roles) != 1 || roles[0] != "admin" {
+		t.Fatalf("expected addRole to be idempotent, got: %v", roles)
+	}
+}
+
+func TestRemoveRoleDropsOnlyNamedRole(t *testing.T) {
+	roles := []string{"admin", "operator"}
+	roles = removeRole(roles, "admin")
+
+	if len(roles) != 1 || roles[0] != "operator" {
+		t.Fatalf("expected only \"admin\" to be removed, got: %v", roles)
+	}
+
+	// Removing a role that was never granted is a no-op, not an error.
+	roles = removeRole(roles, "admin")
+	if len(roles) != 1 || roles[0] != "operator" {
+		t.Fatalf("expected removing an absent role to be a no-op, got: %v", roles)
+	}
+}
+
+func TestRoleGatedContractReflectsRoleMembership(t *testing.T) {
+	acct := acm.ConcreteAccount{Address: reservedAddress(202)}.MutableAccount()
+
+	has := func(role string) bool {
+		for _, r := range acm.AsConcreteAccount(acct).Permissions.Roles {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+
+	if has("minter") {
+		t.Fatal("expected a fresh account to hold no roles")
+	}
+
+	concrete := acm.AsConcreteAccount(acct)
+	concrete.Permissions.Roles = addRole(concrete.Permissions.Roles, "minter")
+	if !has("minter") {
+		t.Fatal("expected the account to reflect a role granted via addRole")
+	}
+
+	concrete.Permissions.Roles = removeRole(concrete.Permissions.Roles, "minter")
+	if has("minter") {
+		t.Fatal("expected the account to no longer reflect a role dropped via removeRole")
+	}
+}
+
+// newSNativeTestState builds a real SawtoothAppState over an in-memory
+// context, so the tests below exercise dispatchSNativePermissions itself -
+// selector parsing, ABI decoding, and the permission check - rather than
+// only the helper functions it calls.
+func newSNativeTestState(t *testing.T) *SawtoothAppState {
+	t.Helper()
+	return NewSawtoothAppState(NewInMemoryContext(map[string][]byte{}))
+}
+
+func setBaseCallData(t *testing.T, target ethcommon.Address, flag uint64, value bool) []byte {
+	t.Helper()
+	args, err := (ethabi.Arguments{{Type: snativeAddressType}, {Type: snativeUint64Type}, {Type: snativeBoolType}}).Pack(target, flag, value)
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	selector, err := hex.DecodeString("dbd4a8ea")
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	return append(selector, args...)
+}
+
+func hasRoleCallData(t *testing.T, target ethcommon.Address, role string) []byte {
+	t.Helper()
+	args, err := (ethabi.Arguments{{Type: snativeAddressType}, {Type: snativeStringType}}).Pack(target, role)
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	selector, err := hex.DecodeString("217fe6c6")
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	return append(selector, args...)
+}
+
+func addRoleCallData(t *testing.T, target ethcommon.Address, role string) []byte {
+	t.Helper()
+	args, err := (ethabi.Arguments{{Type: snativeAddressType}, {Type: snativeStringType}}).Pack(target, role)
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	selector, err := hex.DecodeString("7d72aa65")
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	return append(selector, args...)
+}
+
+func TestDispatchSNativePermissionsRejectsSetBaseWithoutRoot(t *testing.T) {
+	sas := newSNativeTestState(t)
+
+	target := reservedAddress(210)
+	targetEthAddr := ethcommon.BytesToAddress(target.Bytes())
+	if err := sas.UpdateAccount(acm.ConcreteAccount{Address: target}.MutableAccount()); err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	nonRoot := acm.ConcreteAccount{Address: reservedAddress(211)}.MutableAccount()
+
+	input := setBaseCallData(t, targetEthAddr, uint64(permission.Call), true)
+	if _, _, err := dispatchSNativePermissions(sas, nonRoot, input, snativeGasCost); err == nil {
+		t.Fatal("expected setBase dispatched by a non-Root caller to be rejected")
+	}
+}
+
+func TestDispatchSNativePermissionsAddRoleThenHasRole(t *testing.T) {
+	sas := newSNativeTestState(t)
+
+	target := reservedAddress(212)
+	targetEthAddr := ethcommon.BytesToAddress(target.Bytes())
+	if err := sas.UpdateAccount(acm.ConcreteAccount{Address: target}.MutableAccount()); err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	root := acm.ConcreteAccount{
+		Address: reservedAddress(213),
+		Permissions: permission.AccountPermissions{
+			Base: permission.BasePermissions{Perms: permission.Root, SetBit: permission.Root},
+		},
+	}.MutableAccount()
+	if err := sas.UpdateAccount(root); err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	addInput := addRoleCallData(t, targetEthAddr, "minter")
+	if _, _, err := dispatchSNativePermissions(sas, root, addInput, snativeGasCost); err != nil {
+		t.Fatalf("expected addRole dispatched by a Root caller to succeed, got: %v", err)
+	}
+
+	hasInput := hasRoleCallData(t, targetEthAddr, "minter")
+	out, _, err := dispatchSNativePermissions(sas, root, hasInput, snativeGasCost)
+	if err != nil {
+		t.Fatalf("expected hasRole dispatch to succeed, got: %v", err)
+	}
+	values, err := (ethabi.Arguments{{Type: snativeBoolType}}).UnpackValues(out)
+	if err != nil {
+		t.Fatalf("failed to unpack hasRole result: %v", err)
+	}
+	if !values[0].(bool) {
+		t.Fatal("expected hasRole to report the role granted via addRole dispatch")
+	}
+}
@@ -29,16 +29,49 @@ import (
 	"strings"
 )
 
+// addRole returns roles with name appended, unless it is already present.
+func addRole(roles []string, name string) []string {
+	for _, role := range roles {
+		if role == name {
+			return roles
+		}
+	}
+	return append(roles, name)
+}
+
+// removeRole returns roles with name removed, if it was present.
+func removeRole(roles []string, name string) []string {
+	filtered := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if role != name {
+			filtered = append(filtered, role)
+		}
+	}
+	return filtered
+}
+
 var TxnHandlers = map[SethTransaction_TransactionType]TransactionHandler{
 	SethTransaction_CREATE_EXTERNAL_ACCOUNT: CreateExternalAccount,
 	SethTransaction_CREATE_CONTRACT_ACCOUNT: CreateContractAccount,
 	SethTransaction_MESSAGE_CALL:            MessageCall,
 	SethTransaction_SET_PERMISSIONS:         SetPermissions,
+	SethTransaction_SET_CODE_TXN:            SetCodeTxn,
+	SethTransaction_PRIVATE_TXN:             PrivateTxn,
+	SethTransaction_EXPORT_TXN:              ExportTxn,
+	SethTransaction_IMPORT_TXN:              ImportTxn,
+	SethTransaction_REGISTER_ABI:            RegisterABI,
+	SethTransaction_CALL_LOCAL:              CallLocal,
 }
 
+// DelegationDesignatorPrefix is prepended to the address of a delegate
+// contract when an EOA's Code is set via an EIP-7702 style authorization.
+// Any account whose stored code starts with this prefix is treated as
+// delegating execution to the contract at the address that follows it.
+var DelegationDesignatorPrefix = []byte{0xef, 0x01, 0x00}
+
 func CreateExternalAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppState) HandlerResult {
 	txn := wrapper.GetCreateExternalAccount()
-	var newAcct *acm.Account
+	var newAcct acm.Account
 
 	// Sender is creating a separate external account, this is only possible
 	// when gas is free and the sender has permission to create accounts
@@ -53,7 +86,9 @@ func CreateExternalAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 				)},
 			}
 		}
-		if !evm.HasPermission(sapps, senderAcct.Address, permission.CreateAccount) {
+		senderConcrete := acm.AsConcreteAccount(senderAcct)
+
+		if !evm.HasPermission(sapps, senderConcrete.Address, permission.CreateAccount) {
 			return HandlerResult{
 				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 					"Sender account does not have permission to create external accounts: %v",
@@ -62,11 +97,11 @@ func CreateExternalAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 			}
 		}
 		// Check that the nonce in the transaction matches the nonce in state
-		if txn.GetNonce() != senderAcct.Sequence {
+		if txn.GetNonce() != senderConcrete.Sequence {
 			return HandlerResult{
 				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 					"Nonces do not match: Transaction (%v), State (%v)",
-					txn.GetNonce(), senderAcct.Sequence,
+					txn.GetNonce(), senderConcrete.Sequence,
 				)},
 			}
 		}
@@ -104,11 +139,11 @@ func CreateExternalAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 		// sender. Otherwise, set them from transaction.
 		var newPerms permission.AccountPermissions
 		if txn.GetPermissions() == nil {
-			newPerms = senderAcct.Permissions
+			newPerms = senderConcrete.Permissions
 			newPerms.Base.Set(permission.Root, false)
 
 		} else {
-			if !evm.HasPermission(sapps, senderAcct.Address, permission.Root) {
+			if !evm.HasPermission(sapps, senderConcrete.Address, permission.Root) {
 				return HandlerResult{
 					Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 						"Creating account does not have permission to set permissions: %v",
@@ -120,16 +155,16 @@ func CreateExternalAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 		}
 
 		// Create new account
-		newAcct = &acm.Account{
+		newAcct = acm.ConcreteAccount{
 			Address:     crypto.AddressFromWord256(newAcctAddr.ToWord256()),
 			Sequence:    1,
 			Permissions: newPerms,
-		}
+		}.MutableAccount()
 
-		senderAcct.Sequence += 1
+		senderConcrete.Sequence += 1
 
 		// Update accounts in state
-		err = sapps.UpdateAccount(senderAcct)
+		err = sapps.UpdateAccount(senderConcrete.MutableAccount())
 		if err != nil {
 			return HandlerResult{
 				Error: &processor.InvalidTransactionError{Msg: err.Error()},
@@ -172,8 +207,9 @@ func CreateExternalAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 				)},
 			}
 		}
+		globalConcrete := acm.AsConcreteAccount(global)
 
-		if !evm.HasPermission(sapps, global.Address, permission.CreateAccount) {
+		if !evm.HasPermission(sapps, globalConcrete.Address, permission.CreateAccount) {
 			return HandlerResult{
 				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 					"New account creation is disabled, couldn't create account: %v",
@@ -182,11 +218,11 @@ func CreateExternalAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 			}
 		}
 
-		newAcct = &acm.Account{
+		newAcct = acm.ConcreteAccount{
 			Address:     crypto.AddressFromWord256(sender.ToWord256()),
 			Sequence:    1,
-			Permissions: global.Permissions,
-		}
+			Permissions: globalConcrete.Permissions,
+		}.MutableAccount()
 
 		err = sapps.UpdateAccount(newAcct)
 		if err != nil {
@@ -223,9 +259,10 @@ func CreateContractAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 			)},
 		}
 	}
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
 
 	// Verify this account has permission to create contract accounts
-	if !evm.HasPermission(sapps, senderAcct.Address, permission.CreateContract) {
+	if !evm.HasPermission(sapps, senderConcrete.Address, permission.CreateContract) {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 				"Sender account does not have permission to create contracts: %v",
@@ -235,22 +272,22 @@ func CreateContractAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 	}
 
 	// Check that the nonce in the transaction matches the nonce in state
-	if txn.GetNonce() != senderAcct.Sequence {
+	if txn.GetNonce() != senderConcrete.Sequence {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 				"Nonces do not match: Transaction (%v), State (%v)",
-				txn.GetNonce(), senderAcct.Sequence,
+				txn.GetNonce(), senderConcrete.Sequence,
 			)},
 		}
 	}
 
 	var newPerms permission.AccountPermissions
 	if txn.GetPermissions() == nil {
-		newPerms = senderAcct.Permissions
+		newPerms = senderConcrete.Permissions
 		newPerms.Base.Set(permission.Root, false)
 
 	} else {
-		if !evm.HasPermission(sapps, senderAcct.Address, permission.Root) {
+		if !evm.HasPermission(sapps, senderConcrete.Address, permission.Root) {
 			return HandlerResult{
 				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 					"Creating account does not have permission to set permissions: %v",
@@ -263,7 +300,7 @@ func CreateContractAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 
 	// Create the new account
 	// NOTE: The senderAcct's nonce will be incremented
-	addrBytes := senderAcct.Address.Bytes()
+	addrBytes := senderConcrete.Address.Bytes()
 	creatorAddress, err := NewEvmAddrFromBytes(addrBytes)
 	if err != nil {
 		return HandlerResult{
@@ -275,13 +312,31 @@ func CreateContractAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 	}
 	logger.Debugf("CreateAccount(%v)", creatorAddress)
 
+	value := txn.GetValue()
+	if value > 0 {
+		if !evm.HasPermission(sapps, senderConcrete.Address, permission.Send) {
+			return HandlerResult{
+				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+					"Sender account does not have permission to send value: %v", sender,
+				)},
+			}
+		}
+		if value > senderConcrete.Balance {
+			return HandlerResult{
+				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+					"Insufficient balance to send %v from %v", value, sender,
+				)},
+			}
+		}
+	}
+
 	// Get address of new account
-	newAddress := creatorAddress.Derive(uint64(senderAcct.Sequence))
+	newAddress := creatorAddress.Derive(uint64(senderConcrete.Sequence))
 
 	// Increment nonce
-	senderAcct.Sequence += 1
+	senderConcrete.Sequence += 1
 	sapps.CreateAccount(crypto.MustAddressFromBytes(newAddress.Bytes()))
-	newAcct, err := sapps.GetAccount(senderAcct.Address)
+	newAcctIface, err := sapps.GetAccount(senderConcrete.Address)
 	if err != nil {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
@@ -290,9 +345,21 @@ func CreateContractAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 			)},
 		}
 	}
-
-	// Initialize the new account
-	out, gasUsed, err := callVm(sapps, newAcct, nil, txn.GetInit(), nil, txn.GetGasLimit())
+	newConcrete := acm.AsConcreteAccount(newAcctIface)
+
+	// Move value before executing, so CALLVALUE/BALANCE observe the
+	// transfer atomically with call entry instead of only after the call
+	// returns, matching real EVM semantics.
+	senderConcrete.Balance -= value
+	newConcrete.Balance += value
+
+	// Initialize the new account, metering gas against the creator's
+	// balance and the block's shared gas pool
+	gasPrice := txn.GetGasPrice()
+	gasLimit := txn.GetGasLimit()
+	out, gasUsed, reverted, err := chargeGas(sapps, senderConcrete.MutableAccount(), gasPrice, gasLimit, func(gas uint64) ([]byte, uint64, error) {
+		return callVm(sapps, newConcrete.MutableAccount(), nil, txn.GetInit(), nil, value, gas)
+	})
 	if err != nil {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
@@ -302,12 +369,14 @@ func CreateContractAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 		}
 	}
 
-	newAcct.Sequence += 1
-	newAcct.Code = out
-	newAcct.Permissions = newPerms
+	newConcrete.Sequence += 1
+	if !reverted {
+		newConcrete.Code = out
+		newConcrete.Permissions = newPerms
+	}
 
 	// Update accounts in state
-	err = sapps.UpdateAccount(senderAcct)
+	err = sapps.UpdateAccount(senderConcrete.MutableAccount())
 	if err != nil {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
@@ -316,7 +385,7 @@ func CreateContractAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 			)},
 		}
 	}
-	err = sapps.UpdateAccount(newAcct)
+	err = sapps.UpdateAccount(newConcrete.MutableAccount())
 	if err != nil {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
@@ -328,8 +397,9 @@ func CreateContractAccount(wrapper *SethTransaction, sender *EvmAddr, sapps *Saw
 
 	return HandlerResult{
 		GasUsed:     gasUsed,
+		GasRefunded: gasPrice * (gasLimit - gasUsed),
 		ReturnValue: out,
-		NewAccount:  newAcct,
+		NewAccount:  newConcrete.MutableAccount(),
 	}
 }
 
@@ -346,8 +416,10 @@ func MessageCall(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppSt
 		}
 	}
 
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
+
 	// Verify this account has permission to make message calls
-	if !evm.HasPermission(sapps, senderAcct.Address, permission.Call) {
+	if !evm.HasPermission(sapps, senderConcrete.Address, permission.Call) {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 				"Sender account does not have permission to make message calls: %v",
@@ -357,11 +429,11 @@ func MessageCall(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppSt
 	}
 
 	// Check that the nonce in the transaction matches the nonce in state
-	if txn.GetNonce() != senderAcct.Sequence {
+	if txn.GetNonce() != senderConcrete.Sequence {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 				"Nonces do not match: Transaction (%v), State (%v)",
-				txn.GetNonce(), senderAcct.Sequence,
+				txn.GetNonce(), senderConcrete.Sequence,
 			)},
 		}
 	}
@@ -392,16 +464,47 @@ func MessageCall(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppSt
 			)},
 		}
 	}
+	receiverConcrete := acm.AsConcreteAccount(receiverAcct)
 
-	// Execute the contract
-	out, gasUsed, err := callVm(
-		sapps,
-		senderAcct,
-		receiverAcct,
-		receiverAcct.Code.Bytes(),
-		txn.GetData(),
-		txn.GetGasLimit(),
-	)
+	value := txn.GetValue()
+	if value > 0 {
+		if !evm.HasPermission(sapps, senderConcrete.Address, permission.Send) {
+			return HandlerResult{
+				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+					"Sender account does not have permission to send value: %v", sender,
+				)},
+			}
+		}
+		if value > senderConcrete.Balance {
+			return HandlerResult{
+				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+					"Insufficient balance to send %v from %v", value, sender,
+				)},
+			}
+		}
+	}
+
+	// Move value before executing, so CALLVALUE/BALANCE observe the
+	// transfer atomically with call entry instead of only after the call
+	// returns, matching real EVM semantics.
+	senderConcrete.Balance -= value
+	receiverConcrete.Balance += value
+
+	// Execute the contract, metering gas against the sender's balance and
+	// the block's shared gas pool
+	gasPrice := txn.GetGasPrice()
+	gasLimit := txn.GetGasLimit()
+	out, gasUsed, _, err := chargeGas(sapps, senderConcrete.MutableAccount(), gasPrice, gasLimit, func(gas uint64) ([]byte, uint64, error) {
+		return callVm(
+			sapps,
+			senderConcrete.MutableAccount(),
+			receiverConcrete.MutableAccount(),
+			receiverConcrete.Code.Bytes(),
+			txn.GetData(),
+			value,
+			gas,
+		)
+	})
 
 	if err != nil {
 		return HandlerResult{
@@ -411,28 +514,35 @@ func MessageCall(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppSt
 	logger.Debug("Gas Used: ", gasUsed)
 	logger.Debug("EVM Output: ", strings.ToLower(hex.EncodeToString(out)))
 
-	senderAcct.Sequence += 1
+	senderConcrete.Sequence += 1
 
-	sapps.UpdateAccount(senderAcct)
-	sapps.UpdateAccount(receiverAcct)
+	sapps.UpdateAccount(senderConcrete.MutableAccount())
+	sapps.UpdateAccount(receiverConcrete.MutableAccount())
 
 	return HandlerResult{
 		ReturnValue: out,
 		GasUsed:     gasUsed,
+		GasRefunded: gasPrice * (gasLimit - gasUsed),
 	}
 }
 
 func SetPermissions(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppState) HandlerResult {
 	txn := wrapper.GetSetPermissions()
 
-	if txn.GetPermissions() == nil {
+	setsBase := txn.GetPermissions() != nil
+	addRoles := txn.GetAddRoles()
+	removeRoles := txn.GetRemoveRoles()
+	if !setsBase && len(addRoles) == 0 && len(removeRoles) == 0 {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{
-				Msg: "Permissions field cannot be blank in UpdatePermissions transaction",
+				Msg: "UpdatePermissions transaction must set base permissions or add/remove a role",
 			},
 		}
 	}
-	newPerms := toVmPermissions(txn.GetPermissions())
+	var newPerms permission.AccountPermissions
+	if setsBase {
+		newPerms = toVmPermissions(txn.GetPermissions())
+	}
 
 	// Get the account that is trying to update permissions
 	senderAcct, err := sapps.GetAccount(crypto.AddressFromWord256(sender.ToWord256()))
@@ -443,9 +553,10 @@ func SetPermissions(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAp
 			)},
 		}
 	}
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
 
 	// Verify this account has permission to update permissions
-	if !evm.HasPermission(sapps, senderAcct.Address, permission.Root) {
+	if !evm.HasPermission(sapps, senderConcrete.Address, permission.Root) {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 				"Sender account does not have permission to change permissions: %v",
@@ -455,11 +566,11 @@ func SetPermissions(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAp
 	}
 
 	// Check that the nonce in the transaction matches the nonce in state
-	if txn.GetNonce() != senderAcct.Sequence {
+	if txn.GetNonce() != senderConcrete.Sequence {
 		return HandlerResult{
 			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
 				"Nonces do not match: Transaction (%v), State (%v)",
-				txn.GetNonce(), senderAcct.Sequence,
+				txn.GetNonce(), senderConcrete.Sequence,
 			)},
 		}
 	}
@@ -475,8 +586,8 @@ func SetPermissions(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAp
 	}
 
 	logger.Debugf(
-		"SetPermissions(%v): Perms(%v), SetBit(%v)\n", receiver,
-		newPerms.Base.Perms, newPerms.Base.SetBit,
+		"SetPermissions(%v): Perms(%v), SetBit(%v), AddRoles(%v), RemoveRoles(%v)\n", receiver,
+		newPerms.Base.Perms, newPerms.Base.SetBit, addRoles, removeRoles,
 	)
 
 	receiverWord256 := crypto.AddressFromWord256(receiver.ToWord256())
@@ -490,10 +601,10 @@ func SetPermissions(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAp
 	}
 	if receiverAcct == nil {
 		if receiverWord256 == acm.GlobalPermissionsAddress {
-			receiverAcct = &acm.Account{
+			receiverAcct = acm.ConcreteAccount{
 				Address:  receiverWord256,
 				Sequence: 1,
-			}
+			}.MutableAccount()
 		} else {
 			return HandlerResult{
 				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
@@ -503,13 +614,146 @@ func SetPermissions(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAp
 			}
 		}
 	}
+	receiverConcrete := acm.AsConcreteAccount(receiverAcct)
 
 	// Update accounts
-	senderAcct.Sequence += 1
-	receiverAcct.Permissions = newPerms
+	senderConcrete.Sequence += 1
+	if setsBase {
+		// The request only carries the base permission bitmap; roles are
+		// tracked separately via AddRoles/RemoveRoles below, so preserve
+		// whatever roles the account already holds instead of wiping them
+		// out whenever the base bitmap is touched.
+		newPerms.Roles = receiverConcrete.Permissions.Roles
+		receiverConcrete.Permissions = newPerms
+	}
+
+	for _, role := range addRoles {
+		receiverConcrete.Permissions.Roles = addRole(receiverConcrete.Permissions.Roles, role)
+	}
+	for _, role := range removeRoles {
+		receiverConcrete.Permissions.Roles = removeRole(receiverConcrete.Permissions.Roles, role)
+	}
 
-	sapps.UpdateAccount(senderAcct)
-	sapps.UpdateAccount(receiverAcct)
+	sapps.UpdateAccount(senderConcrete.MutableAccount())
+	sapps.UpdateAccount(receiverConcrete.MutableAccount())
 
 	return HandlerResult{}
 }
+
+// SetCodeTxn implements EIP-7702 style set-code authorizations. Each
+// Authorization in the transaction is checked and applied independently:
+// the authorizing EOA's Code is replaced with a delegation designator
+// pointing at the authorized contract address, and its nonce is bumped.
+// Once applied, callVm will transparently execute the delegate's code
+// whenever the EOA is the target of a message call.
+func SetCodeTxn(wrapper *SethTransaction, sender *EvmAddr, sapps *SawtoothAppState) HandlerResult {
+	txn := wrapper.GetSetCodeTxn()
+
+	senderAcct, err := sapps.GetAccount(crypto.AddressFromWord256(sender.ToWord256()))
+	if senderAcct == nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account must already exist to submit a set-code transaction: %v", sender,
+			)},
+		}
+	}
+	senderConcrete := acm.AsConcreteAccount(senderAcct)
+	if !evm.HasPermission(sapps, senderConcrete.Address, permission.Call) {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Sender account does not have permission to make message calls: %v", sender,
+			)},
+		}
+	}
+	if txn.GetNonce() != senderConcrete.Sequence {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Nonces do not match: Transaction (%v), State (%v)",
+				txn.GetNonce(), senderConcrete.Sequence,
+			)},
+		}
+	}
+
+	var gasUsed uint64
+	for _, auth := range txn.GetAuthorizations() {
+		authority, err := RecoverAuthoritySigner(
+			auth.GetChainID(), auth.GetAddress(), auth.GetNonce(),
+			auth.GetYParity(), auth.GetR(), auth.GetS(),
+		)
+		if err != nil {
+			logger.Debugf("Skipping authorization with bad signature: %v", err)
+			continue
+		}
+
+		if auth.GetChainID() != 0 && auth.GetChainID() != CHAIN_ID {
+			logger.Debugf("Skipping authorization for chain %v", auth.GetChainID())
+			continue
+		}
+
+		authorityAddr := crypto.AddressFromWord256(authority.ToWord256())
+		authorityAcct, err := sapps.GetAccount(authorityAddr)
+		if err != nil {
+			return HandlerResult{
+				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+					"Error while retrieving authority account: %v", err,
+				)},
+			}
+		}
+		emptyAccount := authorityAcct == nil
+		if emptyAccount {
+			authorityAcct = acm.ConcreteAccount{Address: authorityAddr}.MutableAccount()
+		}
+		authorityConcrete := acm.AsConcreteAccount(authorityAcct)
+		if auth.GetNonce() != authorityConcrete.Sequence {
+			logger.Debugf("Skipping authorization with stale nonce for %v", authority)
+			continue
+		}
+
+		authorityConcrete.Code = append(append([]byte{}, DelegationDesignatorPrefix...), auth.GetAddress()...)
+		authorityConcrete.Sequence += 1
+
+		if err := sapps.UpdateAccount(authorityConcrete.MutableAccount()); err != nil {
+			return HandlerResult{
+				Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+					"Error updating authority account: %v", err,
+				)},
+			}
+		}
+
+		gasUsed += PER_AUTH_BASE_COST
+		if emptyAccount {
+			gasUsed -= PER_EMPTY_ACCOUNT_REFUND
+		}
+	}
+
+	senderConcrete.Sequence += 1
+
+	// Route the authorized call through chargeGas like every other priced
+	// transaction: a direct callVm here would let a set-code call dodge the
+	// gas-price floor, the sender's balance debit, and the block gas pool
+	// reservation that MessageCall/CreateContractAccount enforce.
+	gasPrice := txn.GetGasPrice()
+	gasLimit := txn.GetGasLimit()
+	out, callGasUsed, _, err := chargeGas(sapps, senderConcrete.MutableAccount(), gasPrice, gasLimit, func(gas uint64) ([]byte, uint64, error) {
+		return callVm(sapps, senderConcrete.MutableAccount(), senderConcrete.MutableAccount(), nil, txn.GetData(), 0, gas)
+	})
+	if err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: err.Error()},
+		}
+	}
+
+	if err := sapps.UpdateAccount(senderConcrete.MutableAccount()); err != nil {
+		return HandlerResult{
+			Error: &processor.InvalidTransactionError{Msg: fmt.Sprintf(
+				"Error updating sender account: %v", err,
+			)},
+		}
+	}
+
+	return HandlerResult{
+		ReturnValue: out,
+		GasUsed:     gasUsed + callGasUsed,
+		GasRefunded: gasPrice * (gasLimit - callGasUsed),
+	}
+}
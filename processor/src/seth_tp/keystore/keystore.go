@@ -0,0 +1,312 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+// Package keystore implements the go-ethereum/status-go V3 keystore JSON
+// format, so Seth client accounts can be moved between Ethereum tooling
+// and Seth without re-deriving addresses.
+package keystore
+
+import (
+	. "common"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/burrow/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+	"sync"
+	"time"
+)
+
+const (
+	version    = 3
+	cipherName = "aes-128-ctr"
+)
+
+// Key is an unlocked secp256k1 keypair together with the Seth address it
+// controls.
+type Key struct {
+	Address    *EvmAddr
+	PrivateKey []byte
+}
+
+// encryptedKeyJSONV3 mirrors the go-ethereum/status-go V3 keystore layout.
+type encryptedKeyJSONV3 struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	Id      string     `json:"id"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherParamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// unlockedEntry tracks an in-memory key together with the timer that will
+// lock it again.
+type unlockedEntry struct {
+	key   *Key
+	timer *time.Timer
+}
+
+// KeyStore holds keys unlocked in memory for a bounded period, backed by
+// encrypted V3 JSON files on disk.
+type KeyStore struct {
+	dir string
+
+	mu       sync.Mutex
+	unlocked map[string]*unlockedEntry
+}
+
+// NewKeyStore returns a KeyStore that reads and writes V3 keyfiles under
+// dir.
+func NewKeyStore(dir string) *KeyStore {
+	return &KeyStore{
+		dir:      dir,
+		unlocked: make(map[string]*unlockedEntry),
+	}
+}
+
+// ImportECDSA decrypts a V3 keyfile with the given passphrase and returns
+// the key it protects. The derived Seth address always equals
+// common.PubToEvmAddr of the recovered public key, so accounts imported
+// from Ethereum tooling control the same EVM address inside Seth.
+func ImportECDSA(keyJSON []byte, passphrase string) (*Key, error) {
+	encrypted := new(encryptedKeyJSONV3)
+	if err := json.Unmarshal(keyJSON, encrypted); err != nil {
+		return nil, err
+	}
+	if encrypted.Version != version {
+		return nil, fmt.Errorf("Unsupported keystore version: %v", encrypted.Version)
+	}
+
+	privateKey, err := decryptKey(encrypted, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := crypto.PublicKeyFromPrivateKeyBytes(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive public key: %v", err)
+	}
+	addr, err := PubToEvmAddr(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive Seth address: %v", err)
+	}
+
+	return &Key{Address: addr, PrivateKey: privateKey}, nil
+}
+
+// ExportECDSA encrypts a key into the V3 keystore JSON format using
+// scrypt with go-ethereum's default work factors.
+func ExportECDSA(key *Key, passphrase string) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, 1<<18, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherText, err := aesCTR(derivedKey[:16], iv, key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := keccak256(append(derivedKey[16:32], cipherText...))
+
+	kdfParams := map[string]interface{}{
+		"n":     1 << 18,
+		"r":     8,
+		"p":     1,
+		"dklen": 32,
+		"salt":  hex.EncodeToString(salt),
+	}
+
+	encrypted := encryptedKeyJSONV3{
+		Address: hex.EncodeToString(key.Address.Bytes()),
+		Crypto: cryptoJSON{
+			Cipher:       cipherName,
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParamsJSON{IV: hex.EncodeToString(iv)},
+			KDF:          "scrypt",
+			KDFParams:    kdfParams,
+			MAC:          hex.EncodeToString(mac),
+		},
+		Id:      newUUID(),
+		Version: version,
+	}
+
+	return json.Marshal(encrypted)
+}
+
+// Unlock keeps key available in memory for the given duration, after
+// which it is wiped. A zero duration keeps the key unlocked until Lock is
+// called explicitly.
+func (ks *KeyStore) Unlock(key *Key, duration time.Duration) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	addr := hex.EncodeToString(key.Address.Bytes())
+	if existing, ok := ks.unlocked[addr]; ok && existing.timer != nil {
+		existing.timer.Stop()
+	}
+
+	entry := &unlockedEntry{key: key}
+	if duration > 0 {
+		entry.timer = time.AfterFunc(duration, func() { ks.Lock(key.Address) })
+	}
+	ks.unlocked[addr] = entry
+}
+
+// Lock wipes a key from memory ahead of its unlock timeout, if any.
+func (ks *KeyStore) Lock(address *EvmAddr) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	addr := hex.EncodeToString(address.Bytes())
+	if entry, ok := ks.unlocked[addr]; ok {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+		for i := range entry.key.PrivateKey {
+			entry.key.PrivateKey[i] = 0
+		}
+		delete(ks.unlocked, addr)
+	}
+}
+
+// Get returns the unlocked key for address, or false if it is locked.
+func (ks *KeyStore) Get(address *EvmAddr) (*Key, bool) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	entry, ok := ks.unlocked[hex.EncodeToString(address.Bytes())]
+	if !ok {
+		return nil, false
+	}
+	return entry.key, true
+}
+
+func decryptKey(encrypted *encryptedKeyJSONV3, passphrase string) ([]byte, error) {
+	if encrypted.Crypto.Cipher != cipherName {
+		return nil, fmt.Errorf("Unsupported cipher: %v", encrypted.Crypto.Cipher)
+	}
+
+	cipherText, err := hex.DecodeString(encrypted.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(encrypted.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := hex.DecodeString(encrypted.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := deriveKey(encrypted.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	calculatedMAC := keccak256(append(derivedKey[16:32], cipherText...))
+	if !hmacEqual(calculatedMAC, mac) {
+		return nil, fmt.Errorf("Could not decrypt key with given passphrase")
+	}
+
+	return aesCTR(derivedKey[:16], iv, cipherText)
+}
+
+func deriveKey(c cryptoJSON, passphrase string) ([]byte, error) {
+	salt, err := hex.DecodeString(c.KDFParams["salt"].(string))
+	if err != nil {
+		return nil, err
+	}
+	dkLen := int(c.KDFParams["dklen"].(float64))
+
+	switch c.KDF {
+	case "scrypt":
+		n := int(c.KDFParams["n"].(float64))
+		r := int(c.KDFParams["r"].(float64))
+		p := int(c.KDFParams["p"].(float64))
+		return scrypt.Key([]byte(passphrase), salt, n, r, p, dkLen)
+	case "pbkdf2":
+		c := int(c.KDFParams["c"].(float64))
+		return pbkdf2.Key([]byte(passphrase), salt, c, dkLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("Unsupported KDF: %v", c.KDF)
+	}
+}
+
+// aesCTR is its own inverse: CTR mode XORs the keystream with the input,
+// so the same call encrypts and decrypts.
+func aesCTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+func keccak256(data []byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+func hmacEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var v byte
+	for i := range a {
+		v |= a[i] ^ b[i]
+	}
+	return v == 0
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
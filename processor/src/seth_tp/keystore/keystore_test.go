@@ -0,0 +1,172 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package keystore
+
+import (
+	. "common"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"golang.org/x/crypto/pbkdf2"
+	"testing"
+)
+
+// This is the standard scrypt V3 keystore fixture from the Ethereum
+// wiki's "Web3 Secret Storage Definition" page (also shipped by
+// go-ethereum as its own keystore test vector), encrypting private key
+// 7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d under
+// the passphrase "testpassword". Decrypting it here against the known
+// plaintext checks this package's scrypt path against a real, independently
+// produced ciphertext rather than only round-tripping its own output.
+const scryptV3Vector = `{
+	"address": "008aeeda4d805471df9b2a5b0f38a0c3bcba786b",
+	"crypto": {
+		"cipher": "aes-128-ctr",
+		"cipherparams": {
+			"iv": "83dbcc02d8ccb40e466191a123791e0e"
+		},
+		"ciphertext": "d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c",
+		"kdf": "scrypt",
+		"kdfparams": {
+			"dklen": 32,
+			"n": 262144,
+			"r": 1,
+			"p": 8,
+			"salt": "ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"
+		},
+		"mac": "2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"
+	},
+	"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+	"version": 3
+}`
+
+const scryptV3VectorPassphrase = "testpassword"
+const scryptV3VectorPrivateKey = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+
+func TestImportECDSAScryptVector(t *testing.T) {
+	key, err := ImportECDSA([]byte(scryptV3Vector), scryptV3VectorPassphrase)
+	if err != nil {
+		t.Fatalf("expected the wiki scrypt vector to decrypt, got: %v", err)
+	}
+
+	want, err := hex.DecodeString(scryptV3VectorPrivateKey)
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	if !bytes.Equal(key.PrivateKey, want) {
+		t.Fatalf("recovered private key %x does not match expected %x", key.PrivateKey, want)
+	}
+}
+
+func TestImportECDSAScryptVectorWrongPassphraseFails(t *testing.T) {
+	if _, err := ImportECDSA([]byte(scryptV3Vector), "wrong passphrase"); err == nil {
+		t.Fatal("expected decryption with the wrong passphrase to fail the MAC check")
+	}
+}
+
+// TestImportECDSAPbkdf2Vector exercises the pbkdf2 KDF path. Unlike the
+// scrypt case above, the Ethereum wiki's pbkdf2 example wasn't recalled
+// with confidence byte-for-byte, so this builds its own known-answer
+// fixture with the stdlib pbkdf2 implementation and checks ImportECDSA
+// recovers the exact plaintext private key it started from - still a
+// real cross-implementation check, since ImportECDSA never sees the
+// plaintext key or derived key directly, only the on-disk JSON shape.
+func TestImportECDSAPbkdf2Vector(t *testing.T) {
+	plainKey, err := hex.DecodeString(scryptV3VectorPrivateKey)
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	salt, err := hex.DecodeString("ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba1")
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	iv, err := hex.DecodeString("83dbcc02d8ccb40e466191a123791e0e")
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	const passphrase = "testpassword"
+	const c = 262144
+	const dkLen = 32
+
+	derivedKey := pbkdf2.Key([]byte(passphrase), salt, c, dkLen, sha256.New)
+
+	cipherText, err := aesCTR(derivedKey[:16], iv, plainKey)
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+	mac := keccak256(append(derivedKey[16:32], cipherText...))
+
+	encrypted := encryptedKeyJSONV3{
+		Address: "008aeeda4d805471df9b2a5b0f38a0c3bcba786b",
+		Crypto: cryptoJSON{
+			Cipher:       cipherName,
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParamsJSON{IV: hex.EncodeToString(iv)},
+			KDF:          "pbkdf2",
+			KDFParams: map[string]interface{}{
+				"c":     float64(c),
+				"dklen": float64(dkLen),
+				"prf":   "hmac-sha256",
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(mac),
+		},
+		Id:      "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		Version: version,
+	}
+	keyJSON, err := json.Marshal(encrypted)
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	key, err := ImportECDSA(keyJSON, passphrase)
+	if err != nil {
+		t.Fatalf("expected the pbkdf2 vector to decrypt, got: %v", err)
+	}
+	if !bytes.Equal(key.PrivateKey, plainKey) {
+		t.Fatalf("recovered private key %x does not match expected %x", key.PrivateKey, plainKey)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	plainKey, err := hex.DecodeString(scryptV3VectorPrivateKey)
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	addr, err := NewEvmAddrFromBytes(make([]byte, 20))
+	if err != nil {
+		t.Fatalf("bad test fixture: %v", err)
+	}
+
+	exported, err := ExportECDSA(&Key{Address: addr, PrivateKey: plainKey}, "round-trip passphrase")
+	if err != nil {
+		t.Fatalf("ExportECDSA failed: %v", err)
+	}
+
+	imported, err := ImportECDSA(exported, "round-trip passphrase")
+	if err != nil {
+		t.Fatalf("ImportECDSA of freshly exported keyfile failed: %v", err)
+	}
+	if !bytes.Equal(imported.PrivateKey, plainKey) {
+		t.Fatalf("round-tripped private key %x does not match original %x", imported.PrivateKey, plainKey)
+	}
+}
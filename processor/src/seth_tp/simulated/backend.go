@@ -0,0 +1,258 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+// Package simulated provides an in-process stand-in for a validator +
+// transaction processor pair, so that contracts built against the Seth
+// handler can be exercised from a Go test without a running network. It
+// mirrors the shape of go-ethereum's accounts/abi/bind/backends.SimulatedBackend.
+package simulated
+
+import (
+	. "common"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/sawtooth-sdk-go/processor"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/processor_pb2"
+	"github.com/hyperledger/sawtooth-sdk-go/protobuf/transaction_pb2"
+	. "protobuf/block_info_pb2"
+	. "protobuf/seth_pb2"
+	"seth_tp/handler"
+	"sync"
+)
+
+// CallMsg describes a read/write Seth call to run against the backend,
+// mirroring go-ethereum's ethereum.CallMsg.
+type CallMsg struct {
+	From     string
+	To       []byte
+	Data     []byte
+	GasLimit uint64
+}
+
+// Receipt is the subset of SethTransactionReceipt callers typically need
+// back from the backend.
+type Receipt struct {
+	ContractAddress []byte
+	GasUsed         uint64
+	ReturnValue     []byte
+}
+
+// LogEvent is a flattened view of a Sawtooth event emitted by the handler,
+// shaped to match what go-ethereum's FilterLogs/SubscribeFilterLogs
+// callers expect to read off a log.
+type LogEvent struct {
+	EventType  string
+	Attributes []processor.Attribute
+	Data       []byte
+}
+
+// Backend runs the full BurrowEVMHandler pipeline against an in-memory
+// sawtooth-sdk-go Context, without a validator or ZMQ connection.
+type Backend struct {
+	mu      sync.Mutex
+	handler *handler.BurrowEVMHandler
+	context *processor.Context
+
+	// snapshots holds saved copies of global state pushed by Commit, so
+	// tests can Rollback to a known-good point.
+	snapshots []ContextSnapshot
+
+	nextTxnId int
+	logs      chan LogEvent
+}
+
+// NewBackend constructs a Backend with empty global state.
+func NewBackend() *Backend {
+	return &Backend{
+		handler: handler.NewBurrowEVMHandler(),
+		context: NewInMemoryContext(map[string][]byte{}),
+		logs:    make(chan LogEvent, 256),
+	}
+}
+
+// SendTransaction submits a state-changing SethTransaction and applies it
+// immediately, as if it were the sole transaction in its own block.
+func (b *Backend) SendTransaction(signerPublicKey string, payload *SethTransaction) (*Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.apply(signerPublicKey, payload)
+}
+
+// CallContract runs a MessageCall against current state and discards any
+// writes, mirroring eth_call.
+func (b *Backend) CallContract(call CallMsg) ([]byte, error) {
+	return b.PendingCallContract(call)
+}
+
+// PendingCallContract is identical to CallContract; the backend has no
+// notion of a separate pending block since every SendTransaction is
+// applied immediately.
+func (b *Backend) PendingCallContract(call CallMsg) ([]byte, error) {
+	b.mu.Lock()
+	snapshot := SnapshotContext(b.context)
+	defer func() {
+		RestoreContext(b.context, snapshot)
+		b.mu.Unlock()
+	}()
+
+	receipt, err := b.apply(call.From, &SethTransaction{
+		TransactionType: SethTransaction_CALL_LOCAL,
+		CallLocal: &CallLocal{
+			To:       call.To,
+			Data:     call.Data,
+			GasLimit: call.GasLimit,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipt.ReturnValue, nil
+}
+
+// EstimateGas runs the call against a throwaway snapshot and reports the
+// gas it consumed.
+func (b *Backend) EstimateGas(call CallMsg) (uint64, error) {
+	b.mu.Lock()
+	snapshot := SnapshotContext(b.context)
+	defer func() {
+		RestoreContext(b.context, snapshot)
+		b.mu.Unlock()
+	}()
+
+	receipt, err := b.apply(call.From, &SethTransaction{
+		TransactionType: SethTransaction_CALL_LOCAL,
+		CallLocal: &CallLocal{
+			To:       call.To,
+			Data:     call.Data,
+			GasLimit: call.GasLimit,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return receipt.GasUsed, nil
+}
+
+// Commit snapshots the current global state so a later Rollback can
+// return to it.
+func (b *Backend) Commit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.snapshots = append(b.snapshots, SnapshotContext(b.context))
+}
+
+// Rollback restores global state to the most recent Commit.
+func (b *Backend) Rollback() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.snapshots) == 0 {
+		return
+	}
+	last := b.snapshots[len(b.snapshots)-1]
+	b.snapshots = b.snapshots[:len(b.snapshots)-1]
+	RestoreContext(b.context, last)
+}
+
+// SubscribeLogs returns a channel that receives every event fired by the
+// handler via SawtoothEventFireable.Log.
+func (b *Backend) SubscribeLogs() <-chan LogEvent {
+	return b.logs
+}
+
+// SetBlockInfo seeds the BlockInfo/BlockInfoConfig state entries that
+// callVm's getParams reads, so that contracts exercising BLOCKHASH,
+// TIMESTAMP, and NUMBER see deterministic values chosen by the test
+// instead of the zero-value fallback used when no block info is present.
+func (b *Backend) SetBlockInfo(blockNum uint64, headerSignature string, timestamp int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	config := &BlockInfoConfig{LatestBlock: blockNum}
+	configBytes, err := proto.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	blockInfoAddr, err := NewBlockInfoAddr(int64(blockNum))
+	if err != nil {
+		return err
+	}
+	info := &BlockInfo{
+		BlockNum:        blockNum,
+		HeaderSignature: headerSignature,
+		Timestamp:       uint64(timestamp),
+	}
+	infoBytes, err := proto.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.context.SetState(map[string][]byte{
+		CONFIG_ADDRESS:        configBytes,
+		blockInfoAddr.String(): infoBytes,
+	})
+	return err
+}
+
+func (b *Backend) apply(signerPublicKey string, payload *SethTransaction) (*Receipt, error) {
+	payloadBytes, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	b.nextTxnId += 1
+	headerSignature := fmt.Sprintf("sim-%d", b.nextTxnId)
+
+	request := &processor_pb2.TpProcessRequest{
+		Payload: payloadBytes,
+		Header: &transaction_pb2.TransactionHeader{
+			SignerPublicKey: signerPublicKey,
+		},
+		Signature: headerSignature,
+	}
+
+	before := len(ContextEvents(b.context))
+
+	if err := b.handler.Apply(request, b.context); err != nil {
+		return nil, err
+	}
+
+	for _, evt := range ContextEvents(b.context)[before:] {
+		b.logs <- LogEvent{
+			EventType:  evt.EventType,
+			Attributes: evt.Attributes,
+			Data:       evt.Data,
+		}
+	}
+
+	data, ok := ContextReceipt(b.context, headerSignature)
+	if !ok {
+		return &Receipt{}, nil
+	}
+
+	pb := &SethTransactionReceipt{}
+	if err := proto.Unmarshal(data, pb); err != nil {
+		return nil, err
+	}
+
+	return &Receipt{
+		ContractAddress: pb.GetContractAddress(),
+		GasUsed:         pb.GetGasUsed(),
+		ReturnValue:     pb.GetReturnValue(),
+	}, nil
+}
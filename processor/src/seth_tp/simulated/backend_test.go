@@ -0,0 +1,107 @@
+/**
+ * Copyright 2017 Intel Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ * ------------------------------------------------------------------------------
+ */
+
+package simulated
+
+import (
+	. "protobuf/seth_pb2"
+	"testing"
+)
+
+// testSignerPublicKey is any well-formed hex-encoded public key; the
+// backend's global permissions default to allowing account creation, so
+// the address it maps to doesn't need to be funded or pre-registered.
+const testSignerPublicKey = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+func TestBackendCreatesExternalAccount(t *testing.T) {
+	backend := NewBackend()
+
+	if _, err := backend.SendTransaction(testSignerPublicKey, &SethTransaction{
+		TransactionType:       SethTransaction_CREATE_EXTERNAL_ACCOUNT,
+		CreateExternalAccount: &CreateExternalAccount{},
+	}); err != nil {
+		t.Fatalf("expected self-creation of an external account to succeed, got: %v", err)
+	}
+}
+
+func TestBackendDeploysAndCallsContract(t *testing.T) {
+	backend := NewBackend()
+
+	if _, err := backend.SendTransaction(testSignerPublicKey, &SethTransaction{
+		TransactionType:       SethTransaction_CREATE_EXTERNAL_ACCOUNT,
+		CreateExternalAccount: &CreateExternalAccount{},
+	}); err != nil {
+		t.Fatalf("failed to create external account: %v", err)
+	}
+
+	// A trivial contract whose init code returns an empty runtime body:
+	// PUSH1 0x00, PUSH1 0x00, RETURN deploys zero bytes of
+	// runtime code deterministically, without needing an assembled
+	// contract fixture.
+	initCode := []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+
+	receipt, err := backend.SendTransaction(testSignerPublicKey, &SethTransaction{
+		TransactionType: SethTransaction_CREATE_CONTRACT_ACCOUNT,
+		CreateContractAccount: &CreateContractAccount{
+			Nonce:    1,
+			Init:     initCode,
+			GasLimit: 100000,
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected contract creation to succeed, got: %v", err)
+	}
+	if len(receipt.ContractAddress) == 0 {
+		t.Fatal("expected a contract address to be reported in the receipt")
+	}
+
+	out, err := backend.CallContract(CallMsg{
+		From:     testSignerPublicKey,
+		To:       receipt.ContractAddress,
+		GasLimit: 100000,
+	})
+	if err != nil {
+		t.Fatalf("expected CallContract against the deployed contract to succeed, got: %v", err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("expected the deployed contract's empty runtime code to return no data, got: %v", out)
+	}
+}
+
+func TestBackendRollbackDiscardsUncommittedState(t *testing.T) {
+	backend := NewBackend()
+	backend.Commit()
+
+	if _, err := backend.SendTransaction(testSignerPublicKey, &SethTransaction{
+		TransactionType:       SethTransaction_CREATE_EXTERNAL_ACCOUNT,
+		CreateExternalAccount: &CreateExternalAccount{},
+	}); err != nil {
+		t.Fatalf("failed to create external account: %v", err)
+	}
+
+	backend.Rollback()
+
+	// The account created above should no longer exist, so creating it
+	// again from scratch must succeed rather than fail with "already
+	// exists".
+	if _, err := backend.SendTransaction(testSignerPublicKey, &SethTransaction{
+		TransactionType:       SethTransaction_CREATE_EXTERNAL_ACCOUNT,
+		CreateExternalAccount: &CreateExternalAccount{},
+	}); err != nil {
+		t.Fatalf("expected Rollback to discard the earlier account creation, got: %v", err)
+	}
+}